@@ -1,70 +1,142 @@
 package log
 
 import (
+	"bufio"
+	"encoding/csv"
 	"fmt"
 	"os"
+	"reflect"
 	"sync"
 	"time"
 )
 
-// CSVLog - used to hold a csv log file
+// CSVLog holds a buffered, RFC 4180 compliant CSV log file
 type CSVLog struct {
-	mux  sync.Mutex
-	file *os.File
+	mux        sync.Mutex
+	file       *os.File
+	buf        *bufio.Writer
+	writer     *csv.Writer
+	timeFormat string
 }
 
-// OpenCSV - opens a CSV file used to detailed logs
+// OpenCSV opens path and writes headers as the first row, rendering
+// time.Time fields with time.RFC3339
 func OpenCSV(path string, headers []interface{}) (*CSVLog, error) {
+	return OpenCSVFormat(path, headers, time.RFC3339)
+}
+
+// OpenCSVFormat opens path like OpenCSV but lets callers choose the
+// layout time.Time fields are rendered with
+func OpenCSVFormat(path string, headers []interface{}, timeFormat string) (*CSVLog, error) {
 	var err error
-	c := new(CSVLog)
+	c := &CSVLog{timeFormat: timeFormat}
 	c.file, err = os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC, os.ModePerm)
 	if Check(err) {
 		return nil, err
 	}
+	c.buf = bufio.NewWriter(c.file)
+	c.writer = csv.NewWriter(c.buf)
 	c.Write(headers)
 	return c, err
 }
 
-// Close the csv log
+// Close flushes any buffered rows and closes the csv log
 func (c *CSVLog) Close() {
 	c.mux.Lock()
 	if nil != c.file {
+		c.writer.Flush()
+		c.buf.Flush()
 		c.file.Close()
 		c.file = nil
 	}
 	c.mux.Unlock()
 }
 
-// Write - writes a row to the csv file
+// Flush writes any buffered rows to disk without closing the file
+func (c *CSVLog) Flush() {
+	c.mux.Lock()
+	if nil != c.file {
+		c.writer.Flush()
+		c.buf.Flush()
+	}
+	c.mux.Unlock()
+}
+
+// Write writes row as a single, properly quoted CSV record
 func (c *CSVLog) Write(row []interface{}) {
 	c.mux.Lock()
 	if nil != c.file {
+		fields := make([]string, len(row))
 		for i, o := range row {
-			switch v := o.(type) {
-			case string:
-				c.file.WriteString(v)
-			case int:
-				c.file.WriteString(fmt.Sprintf("%d", v))
-			case uint8:
-				c.file.WriteString(fmt.Sprintf("%d", v))
-			case uint32:
-				c.file.WriteString(fmt.Sprintf("%d", v))
-			case Hex32:
-				c.file.WriteString(fmt.Sprintf("%#x", int64(v)))
-			case uint16:
-				c.file.WriteString(fmt.Sprintf("%d", v))
-			case bool:
-				c.file.WriteString(fmt.Sprintf("%t", v))
-			case time.Duration:
-				c.file.WriteString(fmt.Sprintf("%d ms", v.Milliseconds()))
-			default:
-				Assert(false, v)
-			}
-			if i < len(row)-1 {
-				c.file.WriteString("\t")
-			}
+			fields[i] = c.field(o)
 		}
-		c.file.WriteString("\n")
+		c.writer.Write(fields)
 	}
 	c.mux.Unlock()
 }
+
+// WriteStruct reflects v into a row and writes it, deriving columns the
+// same way the text log derives fields: exported fields in declaration
+// order, a log:"..." tag renaming nothing (CSV has no header to rename
+// at write time) and log:"hide" skipping the field entirely
+func (c *CSVLog) WriteStruct(v interface{}) {
+	if true == isInterfaceNil(v) {
+		return
+	}
+	t, val := reflectDeref(v)
+	if reflect.Struct != t.Kind() {
+		Assert(false, v)
+		return
+	}
+	row := make([]interface{}, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if "" != t.Field(i).PkgPath {
+			continue
+		}
+		if name, found := t.Field(i).Tag.Lookup("log"); true == found && "hide" == name {
+			continue
+		}
+		row = append(row, val.Field(i).Interface())
+	}
+	c.Write(row)
+}
+
+// field renders o as a CSV field value, falling back to fmt.Stringer and
+// then %v instead of asserting on a type the switch doesn't know
+func (c *CSVLog) field(o interface{}) string {
+	if isInterfaceNil(o) {
+		return ""
+	}
+	switch v := o.(type) {
+	case string:
+		return v
+	case int:
+		return fmt.Sprintf("%d", v)
+	case int32:
+		return fmt.Sprintf("%d", v)
+	case int64:
+		return fmt.Sprintf("%d", v)
+	case uint8:
+		return fmt.Sprintf("%d", v)
+	case uint16:
+		return fmt.Sprintf("%d", v)
+	case uint32:
+		return fmt.Sprintf("%d", v)
+	case Hex32:
+		return fmt.Sprintf("%#x", int64(v))
+	case float32:
+		return fmt.Sprintf("%g", v)
+	case float64:
+		return fmt.Sprintf("%g", v)
+	case bool:
+		return fmt.Sprintf("%t", v)
+	case time.Duration:
+		return fmt.Sprintf("%d ms", v.Milliseconds())
+	case time.Time:
+		return v.Format(c.timeFormat)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// correlationIDKey is the context key NewContext/FromContext use to store
+// a request's correlation ID
+type correlationIDKey struct{}
+
+// NewContext returns a copy of ctx carrying id as its correlation ID
+func NewContext(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// FromContext returns the correlation ID stored in ctx, and whether one
+// was found
+func FromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(correlationIDKey{}).(uuid.UUID)
+	return id, ok
+}
+
+// Entry carries structured fields - a correlation ID, request-scoped
+// key/values - that are attached to every call made through it, closing
+// the gap between the positional a ...interface{} API and the structured
+// fields a distributed service needs for request correlation
+type Entry struct {
+	fields map[string]interface{}
+}
+
+// WithContext starts an Entry seeded with ctx's correlation ID, if any,
+// under the "correlation_id" field
+func WithContext(ctx context.Context) *Entry {
+	e := &Entry{fields: make(map[string]interface{})}
+	if id, ok := FromContext(ctx); true == ok {
+		e.fields["correlation_id"] = id
+	}
+	return e
+}
+
+// WithFields starts an Entry carrying fields
+func WithFields(fields map[string]interface{}) *Entry {
+	return (&Entry{}).WithFields(fields)
+}
+
+// WithFields returns a copy of e with fields merged in, leaving e unmodified
+func (e *Entry) WithFields(fields map[string]interface{}) *Entry {
+	n := &Entry{fields: make(map[string]interface{}, len(e.fields)+len(fields))}
+	for k, v := range e.fields {
+		n.fields[k] = v
+	}
+	for k, v := range fields {
+		n.fields[k] = v
+	}
+	return n
+}
+
+// Warning logs a warning with e's fields attached
+func (e *Entry) Warning(a ...interface{}) {
+	writeLog(&trace{
+		time:   time.Now(),
+		kind:   strWarning,
+		level:  LevelWarning,
+		call:   getCaller(2),
+		data:   a,
+		fields: e.fields,
+	})
+}
+
+// Info logs info with e's fields attached
+func (e *Entry) Info(a ...interface{}) {
+	if LevelInfo < minLevel() {
+		return
+	}
+	writeLog(&trace{
+		time:   time.Now(),
+		kind:   strInfo,
+		level:  LevelInfo,
+		call:   getCaller(2),
+		data:   a,
+		fields: e.fields,
+	})
+}
+
+// Debug writes a debug message with e's fields attached
+func (e *Entry) Debug(a ...interface{}) {
+	if LevelDebug < minLevel() {
+		return
+	}
+	writeLog(&trace{
+		time:   time.Now(),
+		kind:   strDebug,
+		level:  LevelDebug,
+		call:   getCaller(2),
+		stack:  Stack(false),
+		data:   a,
+		fields: e.fields,
+	})
+}
+
+// Trace writes a trace message with e's fields attached
+func (e *Entry) Trace(a ...interface{}) {
+	if LevelTrace < minLevel() {
+		return
+	}
+	writeLog(&trace{
+		time:   time.Now(),
+		kind:   getStructName(a[0]),
+		level:  LevelTrace,
+		call:   getCaller(2),
+		data:   a,
+		trace:  true,
+		fields: e.fields,
+	})
+}
+
+// Fail logs a as an error with e's fields attached; returns a[0] as an
+// error, or nil if a[0] is nil, mirroring the package-level Fail
+func (e *Entry) Fail(a ...interface{}) error {
+	writeLog(&trace{
+		time:   time.Now(),
+		kind:   strError,
+		level:  LevelError,
+		call:   getCaller(2),
+		stack:  Stack(false),
+		data:   a,
+		fields: e.fields,
+	})
+	if nil == a[0] {
+		return nil
+	}
+	return a[0].(error)
+}
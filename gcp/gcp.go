@@ -0,0 +1,124 @@
+// Package gcp implements a log.Sink that forwards Records to Google Cloud
+// Logging and, for errors and asserts, Google Cloud Error Reporting.
+package gcp
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/errorreporting"
+	"cloud.google.com/go/logging"
+	"google.golang.org/api/option"
+
+	"github.com/stevenb256/log"
+)
+
+// Config configures the Sink returned by NewSink
+type Config struct {
+	ProjectID       string
+	ServiceName     string
+	ServiceVersion  string
+	CredentialsFile string
+	BufferSize      int // default 256
+}
+
+// Sink forwards Records to Cloud Logging, and anything at LevelError or
+// above also to Cloud Error Reporting; writes never block the caller
+type Sink struct {
+	logger *logging.Logger
+	lc     *logging.Client
+	er     *errorreporting.Client
+	ch     chan *log.Record
+	done   chan struct{}
+}
+
+// NewSink dials Cloud Logging and Error Reporting and starts the
+// background worker that drains records to them
+func NewSink(cfg Config) (*Sink, error) {
+	ctx := context.Background()
+	var opts []option.ClientOption
+	if "" != cfg.CredentialsFile {
+		opts = append(opts, option.WithCredentialsFile(cfg.CredentialsFile))
+	}
+	lc, err := logging.NewClient(ctx, cfg.ProjectID, opts...)
+	if nil != err {
+		return nil, err
+	}
+	er, err := errorreporting.NewClient(ctx, cfg.ProjectID, errorreporting.Config{
+		ServiceName:    cfg.ServiceName,
+		ServiceVersion: cfg.ServiceVersion,
+	}, opts...)
+	if nil != err {
+		lc.Close()
+		return nil, err
+	}
+	size := cfg.BufferSize
+	if 0 == size {
+		size = 256
+	}
+	s := &Sink{
+		logger: lc.Logger(cfg.ServiceName),
+		lc:     lc,
+		er:     er,
+		ch:     make(chan *log.Record, size),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write enqueues r for the background worker; if the buffer is full the
+// record is dropped rather than stalling the caller
+func (s *Sink) Write(r *log.Record) {
+	select {
+	case s.ch <- r:
+	default:
+	}
+}
+
+// Close drains the queue, flushes the logger, and closes both clients
+func (s *Sink) Close() {
+	close(s.ch)
+	<-s.done
+	s.logger.Flush()
+	s.lc.Close()
+	s.er.Close()
+}
+
+// run drains the queue on a dedicated goroutine so cloud latency never
+// stalls callers
+func (s *Sink) run() {
+	for r := range s.ch {
+		s.logger.Log(logging.Entry{Severity: severity(r.Level), Payload: log.Payload(r)})
+		if log.LevelError <= r.Level {
+			s.er.Report(errorreporting.Entry{Error: recordError(r), Stack: []byte(r.Stack)})
+		}
+	}
+	close(s.done)
+}
+
+// severity maps a log.Level to its Cloud Logging equivalent
+func severity(level log.Level) logging.Severity {
+	switch level {
+	case log.LevelAssert:
+		return logging.Critical
+	case log.LevelError:
+		return logging.Error
+	case log.LevelWarning:
+		return logging.Warning
+	case log.LevelDebug:
+		return logging.Debug
+	}
+	return logging.Info
+}
+
+// recordError finds the first error in r.Data, falling back to r.Kind so
+// Error Reporting always has something to group on
+func recordError(r *log.Record) error {
+	for _, o := range r.Data {
+		if err, ok := o.(error); true == ok {
+			return err
+		}
+	}
+	return errors.New(r.Kind)
+}
@@ -1,14 +1,18 @@
 package log
 
 import (
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fatih/color"
@@ -22,9 +26,26 @@ const strDebug string = "debug"
 const strInfo string = "info"
 const strWarning string = "warning"
 
+// dayLayout is the time.Format layout _fileDay is compared against to
+// detect a day-boundary rotation
+const dayLayout string = "20060102"
+
 // Hex32 -special type to declare hex
 type Hex32 uint32
 
+// Level is a logging severity threshold
+type Level int
+
+// severity levels, lowest to highest
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarning
+	LevelError
+	LevelAssert
+)
+
 // F - shortcut for fmt.Sprintf
 func F(format string, a ...interface{}) string {
 	return fmt.Sprintf(format, a...)
@@ -39,21 +60,172 @@ type caller struct {
 
 // Trace used to hold trace information
 type trace struct {
-	kind  string
-	data  []interface{}
-	stack string
-	time  time.Time
-	call  *caller
-	trace bool
+	kind   string
+	data   []interface{}
+	stack  string
+	time   time.Time
+	call   *caller
+	trace  bool
+	level  Level
+	fields map[string]interface{}
 }
 
 // global log set once initialized
-var _logMux sync.Mutex
 var _build string
 var _file *os.File
 var _consoleInfo bool
 var _consoleTrace bool
 var _currentDir string
+var _sinks []Sink
+var _level Level
+var _rotate *RotateConfig
+var _logPath string
+var _fileDay string
+var _queue chan *trace
+var _queueMux sync.RWMutex // guards _queue against concurrent StartLog/CloseLog
+var _workerDone chan struct{}
+var _overflow OverflowPolicy
+var _queued uint64
+var _dropped uint64
+var _rotateErrors uint64
+
+// SetLevel sets the minimum level that reaches the default file/console
+// output; Debug/Info/Trace calls below it short-circuit before reflection
+// unless a sink attached via SinkAt asks for a lower level, see minLevel
+func SetLevel(level Level) {
+	_level = level
+}
+
+// minLevel returns the lowest threshold among the global level and any
+// per-sink level set via SinkAt, so Debug/Info/Trace don't discard a
+// trace before a more permissive sink ever gets a chance to see it.
+// Takes _queueMux's read side since _sinks can change under StartLog/
+// CloseLog's write lock
+func minLevel() Level {
+	_queueMux.RLock()
+	defer _queueMux.RUnlock()
+	min := _level
+	for _, s := range _sinks {
+		if ls, ok := s.(*leveledSink); true == ok && ls.level < min {
+			min = ls.level
+		}
+	}
+	return min
+}
+
+// OutputFormat selects how a Sink renders a Record
+type OutputFormat int
+
+// output formats
+const (
+	FormatText OutputFormat = iota
+	FormatJSON
+)
+
+// Record is the exported, sink-facing view of a single log entry; sinks
+// must treat it as read-only
+type Record struct {
+	Time     time.Time
+	Kind     string
+	Level    Level
+	File     string
+	Line     int
+	Function string
+	Stack    string
+	Build    string
+	Trace    bool
+	Data     []interface{}
+	Fields   map[string]interface{}
+}
+
+// Sink receives a Record for every logged trace; Write is called from the
+// pipeline's worker goroutine, one record at a time
+type Sink interface {
+	Write(r *Record)
+	Close()
+}
+
+// leveledSink wraps a Sink with its own minimum level, independent of
+// SetLevel which only governs the default file/console output
+type leveledSink struct {
+	level Level
+	sink  Sink
+}
+
+// SinkAt wraps sink so it only receives records at or above level, e.g. to
+// keep a stderr JSON stream at Warning while the file sink stays at Debug
+func SinkAt(level Level, sink Sink) Sink {
+	return &leveledSink{level: level, sink: sink}
+}
+
+// Write drops r if it's below the wrapped sink's level
+func (s *leveledSink) Write(r *Record) {
+	if r.Level < s.level {
+		return
+	}
+	s.sink.Write(r)
+}
+
+// Close closes the wrapped sink
+func (s *leveledSink) Close() {
+	s.sink.Close()
+}
+
+// fileSink writes records to an open file in either text or JSON form
+type fileSink struct {
+	file   *os.File
+	format OutputFormat
+}
+
+// NewFileSink opens path and returns a Sink that appends each Record to it
+// in the given format
+func NewFileSink(path string, format OutputFormat) (Sink, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if nil != err {
+		return nil, err
+	}
+	return &fileSink{file: f, format: format}, nil
+}
+
+// Write writes r to the sink's file
+func (s *fileSink) Write(r *Record) {
+	if FormatJSON == s.format {
+		writeJSON(s.file, r)
+	} else {
+		writeText(s.file, r)
+	}
+}
+
+// Close closes the sink's file
+func (s *fileSink) Close() {
+	s.file.Close()
+}
+
+// consoleSink writes records to an io.Writer such as os.Stderr
+type consoleSink struct {
+	w      io.Writer
+	format OutputFormat
+}
+
+// NewConsoleSink returns a Sink that writes each Record to w in the given
+// format; useful for e.g. a JSON stream on stderr alongside the default
+// colorized console output
+func NewConsoleSink(w io.Writer, format OutputFormat) Sink {
+	return &consoleSink{w: w, format: format}
+}
+
+// Write writes r to the sink's writer
+func (s *consoleSink) Write(r *Record) {
+	if FormatJSON == s.format {
+		writeJSON(s.w, r)
+	} else {
+		writeText(s.w, r)
+	}
+}
+
+// Close is a no-op; consoleSink does not own its writer
+func (s *consoleSink) Close() {
+}
 
 // Check checks if err is a failure; if so logs and returns true; or false
 func Check(a ...interface{}) bool {
@@ -62,6 +234,7 @@ func Check(a ...interface{}) bool {
 			writeLog(&trace{
 				time:  time.Now(),
 				kind:  strError,
+				level: LevelError,
 				call:  getCaller(2),
 				stack: Stack(false),
 				data:  a,
@@ -77,6 +250,7 @@ func Fail(a ...interface{}) error {
 	writeLog(&trace{
 		time:  time.Now(),
 		kind:  strError,
+		level: LevelError,
 		call:  getCaller(2),
 		stack: Stack(false),
 		data:  a,
@@ -93,6 +267,7 @@ func Assert(condition bool, a ...interface{}) {
 		writeLog(&trace{
 			time:  time.Now(),
 			kind:  strAssert,
+			level: LevelAssert,
 			call:  getCaller(2),
 			stack: Stack(false),
 			data:  a,
@@ -105,28 +280,37 @@ func Assert(condition bool, a ...interface{}) {
 // Warning log a warning
 func Warning(a ...interface{}) {
 	writeLog(&trace{
-		time: time.Now(),
-		kind: strWarning,
-		call: getCaller(2),
-		data: a,
+		time:  time.Now(),
+		kind:  strWarning,
+		level: LevelWarning,
+		call:  getCaller(2),
+		data:  a,
 	})
 }
 
 // Info log info
 func Info(a ...interface{}) {
+	if LevelInfo < minLevel() {
+		return
+	}
 	writeLog(&trace{
-		time: time.Now(),
-		kind: strInfo,
-		call: getCaller(2),
-		data: a,
+		time:  time.Now(),
+		kind:  strInfo,
+		level: LevelInfo,
+		call:  getCaller(2),
+		data:  a,
 	})
 }
 
 // Debug write a debug message
 func Debug(a ...interface{}) {
+	if LevelDebug < minLevel() {
+		return
+	}
 	writeLog(&trace{
 		time:  time.Now(),
 		kind:  strDebug,
+		level: LevelDebug,
 		call:  getCaller(2),
 		stack: Stack(false),
 		data:  a,
@@ -135,17 +319,105 @@ func Debug(a ...interface{}) {
 
 // Trace write a trace message
 func Trace(a ...interface{}) {
+	if LevelTrace < minLevel() {
+		return
+	}
 	writeLog(&trace{
 		time:  time.Now(),
 		kind:  getStructName(a[0]),
+		level: LevelTrace,
+		call:  getCaller(2),
+		data:  a,
+		trace: true,
+	})
+}
+
+// TraceTag logs a trace-level message if tag is enabled via the LOGTRACE
+// environment variable, a comma-separated list of tags (or "all"); lets
+// subsystems be selectively enabled without recompiling
+func TraceTag(tag string, a ...interface{}) {
+	if false == tagEnabled(tag) {
+		return
+	}
+	if LevelTrace < minLevel() {
+		return
+	}
+	writeLog(&trace{
+		time:  time.Now(),
+		kind:  tag,
+		level: LevelTrace,
 		call:  getCaller(2),
 		data:  a,
 		trace: true,
 	})
 }
 
-// StartLog initiates and begins logging system
-func StartLog(logFile, build string, consoleInfo, consoleTrace bool) error {
+// _traceTags and _traceAll are populated once from LOGTRACE on first use
+var _traceTagsOnce sync.Once
+var _traceTags map[string]bool
+var _traceAll bool
+
+// tagEnabled reports whether tag is enabled via LOGTRACE
+func tagEnabled(tag string) bool {
+	_traceTagsOnce.Do(loadTraceTags)
+	if true == _traceAll {
+		return true
+	}
+	return _traceTags[tag]
+}
+
+// loadTraceTags parses the LOGTRACE environment variable into _traceTags
+func loadTraceTags() {
+	_traceTags = make(map[string]bool)
+	env := os.Getenv("LOGTRACE")
+	if "" == env {
+		return
+	}
+	for _, tag := range strings.Split(env, ",") {
+		tag = strings.TrimSpace(tag)
+		if "all" == tag {
+			_traceAll = true
+		}
+		_traceTags[tag] = true
+	}
+}
+
+// RotateConfig controls automatic rollover of the default log file; a nil
+// *RotateConfig (the StartLog default) disables rotation entirely
+type RotateConfig struct {
+	MaxSizeBytes int64
+	MaxAgeDays   int
+	MaxBackups   int
+	Compress     bool
+}
+
+// OverflowPolicy controls what happens when the async write pipeline's
+// buffer is full
+type OverflowPolicy int
+
+// overflow policies
+const (
+	Block OverflowPolicy = iota
+	Drop
+	DropOldest
+)
+
+// StartLogOptions configures the asynchronous write pipeline
+type StartLogOptions struct {
+	BufferSize     int // default 1024
+	OverflowPolicy OverflowPolicy
+}
+
+// StartLog initiates and begins logging system; rotate may be nil to keep
+// writing a single unbounded file. Additional sinks (e.g. a JSON file or
+// stderr stream for log-shipping pipelines) can be attached alongside the
+// default text file/console output. Hot paths only pay for an allocation
+// and a channel send: a dedicated goroutine drains the queue into the
+// file/console/sinks
+func StartLog(logFile, build string, consoleInfo, consoleTrace bool, rotate *RotateConfig, opts StartLogOptions, sinks ...Sink) error {
+	_queueMux.Lock()
+	defer _queueMux.Unlock()
+	_rotate = rotate
 	err := openLogFile(logFile)
 	if nil != err {
 		return err
@@ -154,49 +426,240 @@ func StartLog(logFile, build string, consoleInfo, consoleTrace bool) error {
 	_build = build
 	_consoleInfo = consoleInfo
 	_consoleTrace = consoleTrace
+	_sinks = sinks
+	_overflow = opts.OverflowPolicy
+	size := opts.BufferSize
+	if 0 == size {
+		size = 1024
+	}
+	atomic.StoreUint64(&_queued, 0)
+	atomic.StoreUint64(&_dropped, 0)
+	atomic.StoreUint64(&_rotateErrors, 0)
+	_queue = make(chan *trace, size)
+	_workerDone = make(chan struct{})
+	go worker()
 	return nil
 }
 
-// CloseLog shuts down and flushes log
+// CloseLog drains the queue, flushes it to the file/console/sinks, then
+// shuts them down. Holds _queueMux for its entire duration so writeLog
+// can't send on the channel this closes out from under it, and so any
+// writeLog that arrives while CloseLog is running waits until the file
+// and sinks it would otherwise touch are already torn down
 func CloseLog() {
-	_logMux.Lock()
+	_queueMux.Lock()
+	defer _queueMux.Unlock()
+	if nil != _queue {
+		close(_queue)
+		<-_workerDone
+		_queue = nil
+	}
 	if nil != _file {
 		_file.Close()
 		_file = nil
 	}
-	_logMux.Unlock()
+	for _, s := range _sinks {
+		s.Close()
+	}
+	_sinks = nil
 }
 
 // open log file; assume _mux taken
 func openLogFile(logFile string) error {
 	var err error
 	if "" != logFile {
+		_logPath = logFile
 		_file, err = os.OpenFile(logFile,
 			os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC,
 			os.ModePerm)
+		_fileDay = time.Now().Format(dayLayout)
 	}
 	return err
 }
 
-// writes trace info; don't use error handling functions in here
-// assumes _mux.Lock taken
+// rotateIfNeeded rolls the active log file over when it exceeds
+// MaxSizeBytes or crosses a day boundary; only ever called from the
+// single worker goroutine via processTrace, so no locking is needed
+func rotateIfNeeded() {
+	if nil == _rotate || nil == _file || "" == _logPath {
+		return
+	}
+	rotate := time.Now().Format(dayLayout) != _fileDay
+	if false == rotate && 0 < _rotate.MaxSizeBytes {
+		if info, err := _file.Stat(); nil == err && info.Size() >= _rotate.MaxSizeBytes {
+			rotate = true
+		}
+	}
+	if true == rotate {
+		rotateFile()
+	}
+}
+
+// rotateFile closes the active log, renames it with a timestamp suffix,
+// opens a fresh file in its place, and enforces MaxBackups/MaxAgeDays;
+// only ever called from the single worker goroutine via rotateIfNeeded,
+// so no locking is needed
+func rotateFile() {
+	_file.Close()
+	backup := F("%s.%s", _logPath, time.Now().Format("20060102-150405"))
+	os.Rename(_logPath, backup)
+	if true == _rotate.Compress {
+		go compressBackup(backup)
+	}
+	pruneBackups()
+	f, err := os.OpenFile(_logPath, os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if nil != err {
+		atomic.AddUint64(&_rotateErrors, 1)
+		_file = nil
+		return
+	}
+	_file = f
+	_fileDay = time.Now().Format(dayLayout)
+}
+
+// compressBackup gzips a rotated log file in the background, removing the
+// uncompressed copy once the archive is written
+func compressBackup(path string) {
+	src, err := os.Open(path)
+	if nil != err {
+		return
+	}
+	defer src.Close()
+	dst, err := os.Create(path + ".gz")
+	if nil != err {
+		return
+	}
+	defer dst.Close()
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); nil != err {
+		gz.Close()
+		return
+	}
+	if nil != gz.Close() {
+		return
+	}
+	os.Remove(path)
+}
+
+// pruneBackups deletes rotated log files beyond MaxBackups or older than
+// MaxAgeDays
+func pruneBackups() {
+	if 0 == _rotate.MaxBackups && 0 == _rotate.MaxAgeDays {
+		return
+	}
+	matches, err := filepath.Glob(_logPath + ".*")
+	if nil != err {
+		return
+	}
+	sort.Strings(matches) // timestamp suffix sorts chronologically
+	cutoff := time.Now().AddDate(0, 0, -_rotate.MaxAgeDays)
+	for i, m := range matches {
+		old := 0 < _rotate.MaxAgeDays && backupOlderThan(m, cutoff)
+		tooMany := 0 < _rotate.MaxBackups && i < len(matches)-_rotate.MaxBackups
+		if true == old || true == tooMany {
+			os.Remove(m)
+		}
+	}
+}
+
+// backupOlderThan reports whether path's mod time is before cutoff
+func backupOlderThan(path string, cutoff time.Time) bool {
+	info, err := os.Stat(path)
+	if nil != err {
+		return false
+	}
+	return info.ModTime().Before(cutoff)
+}
+
+// writes trace info; don't use error handling functions in here. Enqueues
+// onto the async pipeline rather than writing directly. Holds _queueMux
+// for the read side so a concurrent CloseLog can't close _queue out from
+// under an in-flight send, and so that if no pipeline is running - either
+// before StartLog or after CloseLog - the trace is processed synchronously
+// instead of blocking forever on a nil channel
 func writeLog(t *trace) {
-	_logMux.Lock()
-	if strDebug == t.kind || strAssert == t.kind || strError == t.kind || strWarning == t.kind {
+	_queueMux.RLock()
+	defer _queueMux.RUnlock()
+	if nil == _queue {
+		processTrace(t)
+		return
+	}
+	atomic.AddUint64(&_queued, 1)
+	switch _overflow {
+	case Drop:
+		select {
+		case _queue <- t:
+		default:
+			atomic.AddUint64(&_dropped, 1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case _queue <- t:
+				return
+			default:
+			}
+			select {
+			case <-_queue:
+				atomic.AddUint64(&_dropped, 1)
+			default:
+			}
+		}
+	default:
+		_queue <- t
+	}
+}
+
+// worker drains the queue on a dedicated goroutine so hot paths pay only
+// an allocation and a channel send
+func worker() {
+	for t := range _queue {
+		processTrace(t)
+	}
+	close(_workerDone)
+}
+
+// processTrace performs the actual console/file/sink writes; called from
+// the worker goroutine, or directly from writeLog when no pipeline is
+// running, and takes no lock of its own. Console and file output skip
+// traces below the SetLevel threshold; sinks see every trace
+func processTrace(t *trace) {
+	if t.level < _level {
+		// below SetLevel: only sinks receive it
+	} else if strDebug == t.kind || strAssert == t.kind || strError == t.kind || strWarning == t.kind {
 		writeConsole(t)
 	} else if true == _consoleInfo && strInfo == t.kind {
 		writeConsole(t)
 	} else if true == _consoleTrace && true == t.trace {
 		writeConsole(t)
 	}
-	if nil != _file {
+	if nil != _file && _level <= t.level {
 		writeFile(t)
 	}
-	_logMux.Unlock()
+	if nil != _sinks {
+		r := toRecord(t)
+		for _, s := range _sinks {
+			s.Write(r)
+		}
+	}
+}
+
+// Stats returns the pipeline's lifetime queued and dropped counts
+func Stats() (queued, dropped uint64) {
+	return atomic.LoadUint64(&_queued), atomic.LoadUint64(&_dropped)
+}
+
+// RotateErrors returns the lifetime count of rotations whose reopen of
+// the log file failed; no further rotation is attempted after such a
+// failure, so file logging stays stopped until a fresh StartLog and a
+// non-zero count means file output may have gone dark
+func RotateErrors() uint64 {
+	return atomic.LoadUint64(&_rotateErrors)
 }
 
 // writeFile - writes tab deliminted log file entry
 func writeFile(t *trace) {
+	rotateIfNeeded()
 	dir, file := filepath.Split(t.call.file)
 	fmt.Fprintf(_file, "[%s]\t%02d/%02d/%04d\t%02d:%02d:%02d\t%s:%d\t%s\t",
 		t.kind,
@@ -204,9 +667,180 @@ func writeFile(t *trace) {
 		t.time.Hour(), t.time.Minute(), t.time.Second(),
 		filepath.Base(dir)+"/"+file, t.call.line, t.call.function)
 	writeFields(_file, t, "\t")
+	writeFieldMap(_file, t.fields, "\t")
 	fmt.Fprintf(_file, "\n")
 }
 
+// toRecord converts an internal trace into the exported Record sinks see
+func toRecord(t *trace) *Record {
+	return &Record{
+		Time:     t.time,
+		Kind:     t.kind,
+		Level:    t.level,
+		File:     t.call.file,
+		Line:     t.call.line,
+		Function: t.call.function,
+		Stack:    t.stack,
+		Build:    _build,
+		Trace:    t.trace,
+		Data:     t.data,
+		Fields:   t.fields,
+	}
+}
+
+// writeText writes r to w using the same tab-delimited layout as the
+// default log file
+func writeText(w io.Writer, r *Record) {
+	dir, file := filepath.Split(r.File)
+	fmt.Fprintf(w, "[%s]\t%02d/%02d/%04d\t%02d:%02d:%02d\t%s:%d\t%s\t",
+		r.Kind,
+		r.Time.Month(), r.Time.Day(), r.Time.Year(),
+		r.Time.Hour(), r.Time.Minute(), r.Time.Second(),
+		filepath.Base(dir)+"/"+file, r.Line, r.Function)
+	for i, o := range r.Data {
+		writeField(w, o, "\t")
+		if i < len(r.Data)-1 {
+			fmt.Fprint(w, "\t")
+		}
+	}
+	writeFieldMap(w, r.Fields, "\t")
+	fmt.Fprint(w, "\n")
+}
+
+// writeFieldMap writes a sorted "name:value" list of fields, used to
+// render the correlation ID/structured fields an Entry carries
+func writeFieldMap(w io.Writer, fields map[string]interface{}, delim string) {
+	if 0 == len(fields) {
+		return
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprint(w, delim)
+	for i, k := range keys {
+		fmt.Fprintf(w, "%s:", k)
+		writeField(w, fields[k], delim)
+		if i < len(keys)-1 {
+			fmt.Fprint(w, delim)
+		}
+	}
+}
+
+// jsonRecord is the newline-delimited JSON shape written by writeJSON
+type jsonRecord struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Severity  string                 `json:"severity"`
+	File      string                 `json:"file"`
+	Line      int                    `json:"line"`
+	Function  string                 `json:"function"`
+	Stack     string                 `json:"stack,omitempty"`
+	Build     string                 `json:"build,omitempty"`
+	Payload   interface{}            `json:"payload,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+// writeJSON writes r to w as a single line of newline-delimited JSON
+func writeJSON(w io.Writer, r *Record) {
+	j := jsonRecord{
+		Timestamp: r.Time,
+		Severity:  r.Kind,
+		File:      r.File,
+		Line:      r.Line,
+		Function:  r.Function,
+		Stack:     r.Stack,
+		Build:     r.Build,
+		Payload:   buildPayload(r),
+		Fields:    r.Fields,
+	}
+	b, err := json.Marshal(j)
+	if nil != err {
+		return
+	}
+	w.Write(b)
+	fmt.Fprint(w, "\n")
+}
+
+// Payload reflects r.Data into a JSON-friendly structure the same way the
+// built-in JSON sink does, honoring the log:"..." tag convention including
+// log:"hide" - external sinks (e.g. package gcp) should build their
+// payload through this rather than forwarding r.Data directly, or a
+// hidden field ends up shipped unredacted to whatever it forwards to
+func Payload(r *Record) interface{} {
+	return buildPayload(r)
+}
+
+// buildPayload reflects r.Data into a JSON-friendly structure, honoring
+// the same log tag convention as the text writer (including "hide"); a
+// Trace record with a single struct argument becomes a map of its fields,
+// everything else becomes an array
+func buildPayload(r *Record) interface{} {
+	if nil == r.Data {
+		return nil
+	}
+	if true == r.Trace && 1 == len(r.Data) {
+		if m, ok := buildFieldMap(r.Data[0]); true == ok {
+			return m
+		}
+	}
+	payload := make([]interface{}, 0, len(r.Data))
+	for _, o := range r.Data {
+		payload = append(payload, payloadValue(o))
+	}
+	return payload
+}
+
+// buildFieldMap reflects a struct (or pointer to struct) into a
+// map[string]interface{}, skipping unexported fields and ones tagged
+// log:"hide"
+func buildFieldMap(o interface{}) (map[string]interface{}, bool) {
+	if true == isInterfaceNil(o) {
+		return nil, false
+	}
+	t, v := reflectDeref(o)
+	if reflect.Struct != t.Kind() {
+		return nil, false
+	}
+	m := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		if "" != t.Field(i).PkgPath {
+			continue
+		}
+		name, found := t.Field(i).Tag.Lookup("log")
+		if true == found && "hide" == name {
+			continue
+		} else if false == found || "" == name {
+			name = t.Field(i).Name
+		}
+		m[name] = payloadValue(v.Field(i).Interface())
+	}
+	return m, true
+}
+
+// payloadValue converts a field value into something json.Marshal can
+// render sensibly, falling back to a nested field map for structs
+func payloadValue(o interface{}) interface{} {
+	if true == isInterfaceNil(o) {
+		return nil
+	}
+	switch v := o.(type) {
+	case error:
+		return v.Error()
+	case Hex32:
+		return F("%#x", int64(v))
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		if m, ok := buildFieldMap(o); true == ok {
+			return m
+		}
+		return o
+	}
+}
+
 // write trace to console
 func writeConsole(t *trace) {
 	if strDebug == t.kind {
@@ -224,6 +858,7 @@ func writeConsole(t *trace) {
 		t.kind,
 		t.call.file, t.call.line, t.call.function)
 	writeFields(os.Stdout, t, " ")
+	writeFieldMap(os.Stdout, t.fields, " ")
 	fmt.Fprintf(os.Stdout, "\n")
 	color.Unset()
 }
@@ -368,58 +1003,3 @@ func getCaller(level int) *caller {
 	}
 	return &caller{file: file, line: line, function: f[len(f)-1]}
 }
-
-/* Code to create google logger
-
-ctx := context.Background()
-l.er, err = er.NewClient(
-	ctx, ProjectID,
-	er.Config{
-		ServiceName:    glBuildInfo.Name(),
-		ServiceVersion: Itoa(int64(glBuildInfo.Version)),
-	},
-	option.WithCredentialsFile(Join(*flagHome, "keys.ini")))
-if nil != err {
-	chError <- err
-	return
-}
-defer l.er.Close()
-l.lc, err = lr.NewClient(
-	ctx, ProjectID,
-	option.WithCredentialsFile(Join(*flagHome, "keys.ini")))
-if nil != err {
-	chError <- err
-	return
-}
-defer l.lc.Close()
-l.lr = l.lc.Logger("qloak")
-if strError == trace.Kind {
-	if nil != l.er {
-		l.er.Report(er.Entry{Error: l.Error, Stack: l.Stack})
-	}
-
-
-	else if nil != l.lr {
-		l.lr.log(lr.Entry{Severity: Sev(t), Payload: l.AsJson()})
-
-		er      *er.Client
-		lc      *lr.Client
-		lr      *lr.Logger
-
-		func sev(t *trace) lr.Severity {
-			switch l.Kind {
-			case stringError:
-				return lr.Error
-			case stringTrace:
-				return lr.Info
-			case stringAssert:
-				return lr.Critical
-			case stringDebug:
-				return lr.Debug
-			case stringWarning:
-				return lr.Warning
-			}
-			return lr.Info
-		}
-
-*/
@@ -1,9 +1,18 @@
 package log
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // ErrTest1
@@ -34,13 +43,13 @@ func TestLog(t *testing.T) {
 	int2 := 2
 
 	// fail to start log
-	err := StartLog("/foobar/test.log", "1.0", true, true)
+	err := StartLog("/foobar/test.log", "1.0", true, true, nil, StartLogOptions{})
 	if nil == err {
 		panic("should have failed to start log with invalid path\n")
 	}
 
 	// start log with correct information
-	err = StartLog("testlog.log", "1.0", true, true)
+	err = StartLog("testlog.log", "1.0", true, true, nil, StartLogOptions{})
 	if nil != err {
 		panic("failed to create log when it should have succeeded\n")
 	}
@@ -79,3 +88,321 @@ func TestLog(t *testing.T) {
 	// Close the log file
 	CloseLog()
 }
+
+// jsonPayloadTest exercises the log:"..." tag convention the JSON sink's
+// payload builder honors, including log:"hide"
+type jsonPayloadTest struct {
+	Name   string    `log:"name"`
+	When   time.Time `log:"when"`
+	Secret string    `log:"hide"`
+}
+
+// TestJSONSinkPayload writes a struct with a time.Time and a hidden field
+// through a FormatJSON file sink and checks the payload it renders
+func TestJSONSinkPayload(t *testing.T) {
+	sink, err := NewFileSink("testlog_json.json", FormatJSON)
+	if nil != err {
+		panic("failed to open json sink\n")
+	}
+	defer os.Remove("testlog_json.json")
+
+	err = StartLog("testlog_json.log", "1.0", false, false, nil, StartLogOptions{}, sink)
+	if nil != err {
+		panic("failed to start log\n")
+	}
+	defer os.Remove("testlog_json.log")
+
+	when := time.Date(2026, 7, 29, 10, 30, 0, 0, time.UTC)
+	Trace(&jsonPayloadTest{Name: "test", When: when, Secret: "shhh"})
+	CloseLog()
+
+	b, err := os.ReadFile("testlog_json.json")
+	if nil != err {
+		panic("failed to read json sink output\n")
+	}
+	var rec map[string]interface{}
+	if nil != json.Unmarshal([]byte(strings.TrimSpace(string(b))), &rec) {
+		panic("failed to unmarshal json record\n")
+	}
+	payload, ok := rec["payload"].(map[string]interface{})
+	if false == ok {
+		panic("payload was not a JSON object\n")
+	}
+	if _, found := payload["Secret"]; true == found {
+		panic("log:\"hide\" field leaked into the JSON payload\n")
+	}
+	if _, found := payload["hide"]; true == found {
+		panic("log:\"hide\" field leaked into the JSON payload\n")
+	}
+	if want := when.Format(time.RFC3339Nano); want != payload["when"] {
+		panic(F("want time.Time rendered as %s, got %v\n", want, payload["when"]))
+	}
+}
+
+// captureSink records every Record it's given, for assertions in tests
+type captureSink struct {
+	mux     sync.Mutex
+	records []*Record
+}
+
+// Write appends r to the sink's records
+func (s *captureSink) Write(r *Record) {
+	s.mux.Lock()
+	s.records = append(s.records, r)
+	s.mux.Unlock()
+}
+
+// Close is a no-op; captureSink owns nothing
+func (s *captureSink) Close() {
+}
+
+// TestSinkAtBelowGlobalLevel makes sure a sink attached via
+// SinkAt(LevelDebug, ...) still receives Debug records once
+// SetLevel(LevelWarning) has raised the global threshold - the bug where
+// Debug short-circuited on _level before writeLog, and therefore before
+// any sink ever saw the record
+func TestSinkAtBelowGlobalLevel(t *testing.T) {
+	SetLevel(LevelWarning)
+	defer SetLevel(LevelTrace)
+
+	sink := &captureSink{}
+	err := StartLog("testlog_sinkat.log", "1.0", false, false, nil, StartLogOptions{}, SinkAt(LevelDebug, sink))
+	if nil != err {
+		panic("failed to start log\n")
+	}
+	defer os.Remove("testlog_sinkat.log")
+
+	Debug("should still reach the debug sink")
+	CloseLog()
+
+	sink.mux.Lock()
+	defer sink.mux.Unlock()
+	if 0 == len(sink.records) {
+		panic("SinkAt(LevelDebug, ...) never received the Debug record\n")
+	}
+}
+
+// TestTraceTagFiltering checks that TraceTag only passes through tags
+// enabled via the LOGTRACE environment variable
+func TestTraceTagFiltering(t *testing.T) {
+	os.Setenv("LOGTRACE", "wanted")
+	defer os.Unsetenv("LOGTRACE")
+
+	sink := &captureSink{}
+	err := StartLog("testlog_tracetag.log", "1.0", false, false, nil, StartLogOptions{}, sink)
+	if nil != err {
+		panic("failed to start log\n")
+	}
+	defer os.Remove("testlog_tracetag.log")
+
+	TraceTag("unwanted", "should be filtered out")
+	TraceTag("wanted", "should pass through")
+	CloseLog()
+
+	sink.mux.Lock()
+	defer sink.mux.Unlock()
+	if 1 != len(sink.records) {
+		panic(F("expected exactly 1 record past the LOGTRACE filter, got %d\n", len(sink.records)))
+	}
+	if "wanted" != sink.records[0].Kind {
+		panic(F("expected the \"wanted\" tag to pass, got %q\n", sink.records[0].Kind))
+	}
+}
+
+// TestEntryFields checks that WithContext's correlation ID and WithFields'
+// custom fields both reach the sink on Record.Fields
+func TestEntryFields(t *testing.T) {
+	id := uuid.New()
+	ctx := NewContext(context.Background(), id)
+
+	sink := &captureSink{}
+	err := StartLog("testlog_entry.log", "1.0", false, false, nil, StartLogOptions{}, sink)
+	if nil != err {
+		panic("failed to start log\n")
+	}
+	defer os.Remove("testlog_entry.log")
+
+	WithContext(ctx).WithFields(map[string]interface{}{"user": "alice"}).Info("entry with fields")
+	CloseLog()
+
+	sink.mux.Lock()
+	defer sink.mux.Unlock()
+	if 0 == len(sink.records) {
+		panic("Entry.Info never reached the sink\n")
+	}
+	fields := sink.records[0].Fields
+	if id != fields["correlation_id"] {
+		panic(F("expected correlation_id %v, got %v\n", id, fields["correlation_id"]))
+	}
+	if "alice" != fields["user"] {
+		panic(F("expected user field \"alice\", got %v\n", fields["user"]))
+	}
+}
+
+// csvStructTest exercises WriteStruct's log:"hide" skipping and RFC4180
+// quoting of a field containing a comma
+type csvStructTest struct {
+	Name   string
+	Note   string
+	Secret string `log:"hide"`
+}
+
+// TestCSVWriteStruct checks that WriteStruct skips log:"hide" fields and
+// that a field containing a comma round-trips through encoding/csv
+func TestCSVWriteStruct(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "struct.csv")
+	c, err := OpenCSV(path, []interface{}{"Name", "Note"})
+	if nil != err {
+		panic("failed to open csv log\n")
+	}
+	c.WriteStruct(&csvStructTest{Name: "alice", Note: "hello, world", Secret: "shhh"})
+	c.Close()
+
+	f, err := os.Open(path)
+	if nil != err {
+		panic("failed to reopen csv log\n")
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if nil != err {
+		panic("failed to parse csv output\n")
+	}
+	if 2 != len(rows) {
+		panic(F("expected a header row and one data row, got %d\n", len(rows)))
+	}
+	if want := []string{"Name", "Note"}; want[0] != rows[0][0] || want[1] != rows[0][1] {
+		panic(F("expected header %v, got %v\n", want, rows[0]))
+	}
+	if 2 != len(rows[1]) {
+		panic(F("log:\"hide\" field leaked into the csv row: %v\n", rows[1]))
+	}
+	if "alice" != rows[1][0] || "hello, world" != rows[1][1] {
+		panic(F("expected [alice, hello, world], got %v\n", rows[1]))
+	}
+}
+
+// TestRotationSizeAndPrune forces several size-triggered rotations and
+// checks that MaxBackups prunes the oldest ones
+func TestRotationSizeAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rotate.log")
+	rotate := &RotateConfig{MaxSizeBytes: 1, MaxBackups: 2}
+	err := StartLog(path, "1.0", false, false, rotate, StartLogOptions{})
+	if nil != err {
+		panic("failed to start log\n")
+	}
+	for i := 0; i < 6; i++ {
+		Info("rotate test line", i)
+	}
+	CloseLog()
+
+	matches, err := filepath.Glob(path + ".*")
+	if nil != err {
+		panic("failed to glob rotated backups\n")
+	}
+	if 0 == len(matches) {
+		panic("expected at least one rotated backup file\n")
+	}
+	if len(matches) > rotate.MaxBackups {
+		panic(F("expected MaxBackups to prune to at most %d backups, got %d\n", rotate.MaxBackups, len(matches)))
+	}
+	if _, err := os.Stat(path); nil != err {
+		panic("expected the active log file to still exist after rotation\n")
+	}
+}
+
+// TestRotationCompress checks that a rotated backup is gzipped when
+// RotateConfig.Compress is set
+func TestRotationCompress(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rotate.log")
+	rotate := &RotateConfig{MaxSizeBytes: 1, Compress: true}
+	err := StartLog(path, "1.0", false, false, rotate, StartLogOptions{})
+	if nil != err {
+		panic("failed to start log\n")
+	}
+	Info("first line triggers no rotation")
+	Info("second line rotates the first")
+	CloseLog()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if matches, _ := filepath.Glob(path + ".*.gz"); 0 < len(matches) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	panic("expected a gzipped backup within 1s of CloseLog\n")
+}
+
+// TestWriteLogVsCloseLog races concurrent writers against CloseLog, the
+// scenario that used to panic with "send on closed channel" because
+// nothing synchronized writeLog's enqueue against CloseLog's close
+func TestWriteLogVsCloseLog(t *testing.T) {
+	err := StartLog("testlog_race.log", "1.0", false, false, nil, StartLogOptions{})
+	if nil != err {
+		panic("failed to start log\n")
+	}
+	defer os.Remove("testlog_race.log")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Info("race", n, j)
+			}
+		}(i)
+	}
+	CloseLog()
+	wg.Wait()
+}
+
+// TestWriteLogNoStartLog makes sure logging before StartLog (or after
+// CloseLog) processes the trace synchronously instead of blocking
+// forever on a nil queue
+func TestWriteLogNoStartLog(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		Info("no pipeline running")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		panic("writeLog blocked with no pipeline running\n")
+	}
+}
+
+// BenchmarkInfoPipeline benchmarks Info through the async queue/worker
+// pipeline
+func BenchmarkInfoPipeline(b *testing.B) {
+	StartLog("bench_async.log", "1.0", false, false, nil, StartLogOptions{BufferSize: 4096, OverflowPolicy: Drop})
+	defer os.Remove("bench_async.log")
+	defer CloseLog()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Info("benchmark", i)
+	}
+}
+
+// BenchmarkInfoSync benchmarks a single mutex guarding a direct file
+// write, the throughput ceiling of the old locked implementation, for
+// comparison against BenchmarkInfoPipeline
+func BenchmarkInfoSync(b *testing.B) {
+	f, err := os.OpenFile("bench_sync.log", os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if nil != err {
+		b.Fatal(err)
+	}
+	defer os.Remove("bench_sync.log")
+	defer f.Close()
+	var mux sync.Mutex
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mux.Lock()
+		fmt.Fprintf(f, "benchmark %d\n", i)
+		mux.Unlock()
+	}
+}